@@ -0,0 +1,315 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+// commandHandler handles a single /whentochat sub-command. parameters holds the
+// remaining command arguments with the trigger and sub-command stripped off. ctx is
+// cancelled once the command's deadline (see defaultCommandTimeout) elapses.
+type commandHandler func(p *Plugin, ctx context.Context, args *model.CommandArgs, parameters []string) (*model.CommandResponse, *model.AppError)
+
+// commandHandlers maps each /whentochat sub-command to the function that handles it,
+// following the same pattern as Mattermost's own api/command.go.
+var commandHandlers = map[string]commandHandler{
+	"find":      runFindCommand,
+	"set-hours": runSetHoursCommand,
+	"set-days":  runSetDaysCommand,
+	"exclude":   runExcludeCommand,
+	"show":      runShowCommand,
+	"ics":       runICSCommand,
+	"help":      runHelpCommand,
+}
+
+const defaultSubCommand = "find"
+
+// getAutocompleteData builds the model.AutocompleteData tree registered in OnActivate so
+// users get argument hints for every /whentochat sub-command in the UI.
+func getAutocompleteData() *model.AutocompleteData {
+	whenToChat := model.NewAutocompleteData("whentochat", "[command]", "Find a time to chat!")
+
+	find := model.NewAutocompleteData("find", "", "Find the next time everyone in the channel is free")
+	whenToChat.AddCommand(find)
+
+	setHours := model.NewAutocompleteData("set-hours", "[09:00-17:00]", "Set your working hours")
+	setHours.AddTextArgument("Working hours as HH:MM-HH:MM", "[09:00-17:00]", "")
+	whenToChat.AddCommand(setHours)
+
+	setDays := model.NewAutocompleteData("set-days", "[mon-fri]", "Set your working days")
+	setDays.AddTextArgument("Working days as a day range, e.g. mon-fri", "[mon-fri]", "")
+	whenToChat.AddCommand(setDays)
+
+	exclude := model.NewAutocompleteData("exclude", "@user", "Exclude a user from scheduling suggestions")
+	exclude.AddTextArgument("The user to exclude", "@user", "")
+	whenToChat.AddCommand(exclude)
+
+	show := model.NewAutocompleteData("show", "", "Show your current scheduling preferences")
+	whenToChat.AddCommand(show)
+
+	ics := model.NewAutocompleteData("ics", "", "Get a calendar invite for the earliest suitable window")
+	whenToChat.AddCommand(ics)
+
+	help := model.NewAutocompleteData("help", "", "Show help for /whentochat")
+	whenToChat.AddCommand(help)
+
+	return whenToChat
+}
+
+func (p *Plugin) postCommandResponse(args *model.CommandArgs, message string) *model.CommandResponse {
+	post := &model.Post{
+		UserId:    p.BotUserID,
+		ChannelId: args.ChannelId,
+		Message:   message,
+	}
+	_ = p.API.SendEphemeralPost(args.UserId, post)
+	return &model.CommandResponse{}
+}
+
+func runFindCommand(p *Plugin, ctx context.Context, args *model.CommandArgs, parameters []string) (*model.CommandResponse, *model.AppError) {
+	configuration := p.getConfiguration()
+
+	allUsers, err := p.allUsers(ctx, args.ChannelId, configuration.MaxChannelMembers)
+	if err != nil && !errors.Is(err, errMaxChannelMembers) {
+		return nil, model.NewAppError("whentochat (*Plugin).runFindCommand", "", nil, err.Error(), http.StatusInternalServerError)
+	}
+
+	if err != nil && errors.Is(err, errMaxChannelMembers) {
+		return p.postCommandResponse(args, "Too many channel members."), nil
+	}
+
+	foundWindows := p.windows(ctx, allUsers, defaultSearchDays, defaultMinDurationMinutes)
+	if len(foundWindows) == 0 {
+		return p.postCommandResponse(args, fmt.Sprintf("There is no window in the next %d days that suits everyone.", defaultSearchDays)), nil
+	}
+
+	allUsers = arrangeUserFirst(args.UserId, allUsers)
+
+	var message string
+	if len(allUsers) <= maxDisplayUserBullets {
+		message = verboseDisplay(foundWindows, allUsers)
+	} else {
+		message = compactDisplay(foundWindows, allUsers)
+	}
+
+	requestID, err := p.cacheWindow(args.ChannelId, args.UserId, allUsers, foundWindows[0])
+	if err != nil {
+		return nil, model.NewAppError("whentochat (*Plugin).runFindCommand", "", nil, err.Error(), http.StatusInternalServerError)
+	}
+
+	post := &model.Post{
+		UserId:    p.BotUserID,
+		ChannelId: args.ChannelId,
+		Message:   "It looks like the best times to chat are:\n" + message,
+		Props: map[string]interface{}{
+			"attachments": []*model.SlackAttachment{schedulingAttachment(requestID)},
+		},
+	}
+	_ = p.API.SendEphemeralPost(args.UserId, post)
+
+	return &model.CommandResponse{}, nil
+}
+
+func runICSCommand(p *Plugin, ctx context.Context, args *model.CommandArgs, parameters []string) (*model.CommandResponse, *model.AppError) {
+	configuration := p.getConfiguration()
+
+	allUsers, err := p.allUsers(ctx, args.ChannelId, configuration.MaxChannelMembers)
+	if err != nil && !errors.Is(err, errMaxChannelMembers) {
+		return nil, model.NewAppError("whentochat (*Plugin).runICSCommand", "", nil, err.Error(), http.StatusInternalServerError)
+	}
+
+	if err != nil && errors.Is(err, errMaxChannelMembers) {
+		return p.postCommandResponse(args, "Too many channel members."), nil
+	}
+
+	foundWindows := p.windows(ctx, allUsers, defaultSearchDays, defaultMinDurationMinutes)
+	if len(foundWindows) == 0 {
+		return p.postCommandResponse(args, fmt.Sprintf("There is no window in the next %d days that suits everyone.", defaultSearchDays)), nil
+	}
+
+	ics, err := BuildICS("Chat", foundWindows[0].Start, foundWindows[0].End, allUsers)
+	if err != nil {
+		return nil, model.NewAppError("whentochat (*Plugin).runICSCommand", "", nil, err.Error(), http.StatusInternalServerError)
+	}
+
+	fileInfo, appErr := p.API.UploadFile(ics, args.ChannelId, "meeting.ics")
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	post := &model.Post{
+		UserId:    p.BotUserID,
+		ChannelId: args.ChannelId,
+		Message:   "Here's a calendar invite for the earliest suitable window.",
+		FileIds:   []string{fileInfo.Id},
+	}
+	_ = p.API.SendEphemeralPost(args.UserId, post)
+
+	return &model.CommandResponse{}, nil
+}
+
+func runSetHoursCommand(p *Plugin, ctx context.Context, args *model.CommandArgs, parameters []string) (*model.CommandResponse, *model.AppError) {
+	if len(parameters) != 1 {
+		return p.postCommandResponse(args, "Usage: `/whentochat set-hours 09:00-17:00`"), nil
+	}
+
+	earliestHour, latestHour, err := parseHourRange(parameters[0])
+	if err != nil {
+		return p.postCommandResponse(args, fmt.Sprintf("That doesn't look like a valid hour range: %s", err)), nil
+	}
+
+	prefs, err := p.getPrefs(args.UserId)
+	if err != nil {
+		return nil, model.NewAppError("whentochat (*Plugin).runSetHoursCommand", "", nil, err.Error(), http.StatusInternalServerError)
+	}
+	prefs.EarliestHour = earliestHour
+	prefs.LatestHour = latestHour
+
+	if err := p.setPrefs(args.UserId, prefs); err != nil {
+		return nil, model.NewAppError("whentochat (*Plugin).runSetHoursCommand", "", nil, err.Error(), http.StatusInternalServerError)
+	}
+
+	return p.postCommandResponse(args, fmt.Sprintf("Your working hours are now set to %s.", formatHours(prefs))), nil
+}
+
+func runSetDaysCommand(p *Plugin, ctx context.Context, args *model.CommandArgs, parameters []string) (*model.CommandResponse, *model.AppError) {
+	if len(parameters) != 1 {
+		return p.postCommandResponse(args, "Usage: `/whentochat set-days mon-fri`"), nil
+	}
+
+	workdays, err := parseDayRange(parameters[0])
+	if err != nil {
+		return p.postCommandResponse(args, fmt.Sprintf("That doesn't look like a valid day range: %s", err)), nil
+	}
+
+	prefs, err := p.getPrefs(args.UserId)
+	if err != nil {
+		return nil, model.NewAppError("whentochat (*Plugin).runSetDaysCommand", "", nil, err.Error(), http.StatusInternalServerError)
+	}
+	prefs.Workdays = workdays
+
+	if err := p.setPrefs(args.UserId, prefs); err != nil {
+		return nil, model.NewAppError("whentochat (*Plugin).runSetDaysCommand", "", nil, err.Error(), http.StatusInternalServerError)
+	}
+
+	return p.postCommandResponse(args, fmt.Sprintf("Your working days are now set to %s.", parameters[0])), nil
+}
+
+func runExcludeCommand(p *Plugin, ctx context.Context, args *model.CommandArgs, parameters []string) (*model.CommandResponse, *model.AppError) {
+	if len(parameters) != 1 || !strings.HasPrefix(parameters[0], "@") {
+		return p.postCommandResponse(args, "Usage: `/whentochat exclude @user`"), nil
+	}
+
+	username := strings.TrimPrefix(parameters[0], "@")
+	user, appErr := p.API.GetUserByUsername(username)
+	if appErr != nil {
+		return p.postCommandResponse(args, fmt.Sprintf("Couldn't find a user named %s.", parameters[0])), nil
+	}
+
+	prefs, err := p.getPrefs(user.Id)
+	if err != nil {
+		return nil, model.NewAppError("whentochat (*Plugin).runExcludeCommand", "", nil, err.Error(), http.StatusInternalServerError)
+	}
+	prefs.Excluded = true
+
+	if err := p.setPrefs(user.Id, prefs); err != nil {
+		return nil, model.NewAppError("whentochat (*Plugin).runExcludeCommand", "", nil, err.Error(), http.StatusInternalServerError)
+	}
+
+	return p.postCommandResponse(args, fmt.Sprintf("%s will be excluded from scheduling suggestions.", parameters[0])), nil
+}
+
+func runShowCommand(p *Plugin, ctx context.Context, args *model.CommandArgs, parameters []string) (*model.CommandResponse, *model.AppError) {
+	prefs, err := p.getPrefs(args.UserId)
+	if err != nil {
+		return nil, model.NewAppError("whentochat (*Plugin).runShowCommand", "", nil, err.Error(), http.StatusInternalServerError)
+	}
+
+	excludedMsg := "no"
+	if prefs.Excluded {
+		excludedMsg = "yes"
+	}
+
+	message := fmt.Sprintf("Your current preferences:\n- Hours: %s\n- Days: %s\n- Excluded: %s",
+		formatHours(prefs), formatWorkdays(prefs.Workdays), excludedMsg)
+	return p.postCommandResponse(args, message), nil
+}
+
+func runHelpCommand(p *Plugin, ctx context.Context, args *model.CommandArgs, parameters []string) (*model.CommandResponse, *model.AppError) {
+	message := "" +
+		"* `/whentochat find` - Find the next time everyone in the channel is free\n" +
+		"* `/whentochat set-hours 09:00-17:00` - Set your working hours\n" +
+		"* `/whentochat set-days mon-fri` - Set your working days\n" +
+		"* `/whentochat exclude @user` - Exclude a user from scheduling suggestions\n" +
+		"* `/whentochat show` - Show your current scheduling preferences\n" +
+		"* `/whentochat ics` - Get a calendar invite for the earliest suitable window\n" +
+		"* `/whentochat help` - Show this help text"
+	return p.postCommandResponse(args, message), nil
+}
+
+// parseHourRange parses an "HH:MM-HH:MM" string such as "09:00-17:00".
+func parseHourRange(s string) (start, end int, err error) {
+	parts := strings.SplitN(s, "-", 2)
+	if len(parts) != 2 {
+		return 0, 0, errors.New("expected format HH:MM-HH:MM")
+	}
+
+	startTime, err := time.Parse("15:04", parts[0])
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "invalid start time")
+	}
+	endTime, err := time.Parse("15:04", parts[1])
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "invalid end time")
+	}
+
+	start, end = startTime.Hour(), endTime.Hour()
+	if start >= end {
+		return 0, 0, errors.New("start time must be before end time")
+	}
+
+	return start, end, nil
+}
+
+var weekdaysByName = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// parseDayRange parses a day range such as "mon-fri" into the list of weekdays it spans.
+func parseDayRange(s string) ([]time.Weekday, error) {
+	parts := strings.SplitN(strings.ToLower(s), "-", 2)
+	if len(parts) != 2 {
+		return nil, errors.New("expected format mon-fri")
+	}
+
+	start, ok := weekdaysByName[parts[0]]
+	if !ok {
+		return nil, errors.Errorf("unknown day %q", parts[0])
+	}
+	end, ok := weekdaysByName[parts[1]]
+	if !ok {
+		return nil, errors.Errorf("unknown day %q", parts[1])
+	}
+
+	var days []time.Weekday
+	for d := start; ; d = (d + 1) % 7 {
+		days = append(days, d)
+		if d == end {
+			break
+		}
+	}
+
+	return days, nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseHourRange(t *testing.T) {
+	t.Run("valid range", func(t *testing.T) {
+		start, end, err := parseHourRange("09:00-17:00")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if start != 9 || end != 17 {
+			t.Errorf("got start=%d end=%d, want start=9 end=17", start, end)
+		}
+	})
+
+	t.Run("minutes are truncated to the hour", func(t *testing.T) {
+		start, end, err := parseHourRange("09:30-17:45")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if start != 9 || end != 17 {
+			t.Errorf("got start=%d end=%d, want start=9 end=17", start, end)
+		}
+	})
+
+	t.Run("start must be before end", func(t *testing.T) {
+		if _, _, err := parseHourRange("17:00-09:00"); err == nil {
+			t.Fatal("expected an error for a reversed range")
+		}
+	})
+
+	t.Run("malformed input", func(t *testing.T) {
+		if _, _, err := parseHourRange("9am-5pm"); err == nil {
+			t.Fatal("expected an error for a malformed range")
+		}
+	})
+}
+
+func TestParseDayRange(t *testing.T) {
+	t.Run("simple range", func(t *testing.T) {
+		days, err := parseDayRange("mon-fri")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		want := []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+		if !equalWeekdays(days, want) {
+			t.Errorf("got %v, want %v", days, want)
+		}
+	})
+
+	t.Run("range wraps around the week", func(t *testing.T) {
+		days, err := parseDayRange("fri-mon")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		want := []time.Weekday{time.Friday, time.Saturday, time.Sunday, time.Monday}
+		if !equalWeekdays(days, want) {
+			t.Errorf("got %v, want %v", days, want)
+		}
+	})
+
+	t.Run("unknown day", func(t *testing.T) {
+		if _, err := parseDayRange("mon-someday"); err == nil {
+			t.Fatal("expected an error for an unknown day")
+		}
+	})
+}
+
+func equalWeekdays(a, b []time.Weekday) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+	"github.com/pkg/errors"
+)
+
+const (
+	actionPostToChannel = "/api/v1/actions/post"
+	actionICS           = "/api/v1/actions/ics"
+	actionTryTomorrow   = "/api/v1/actions/tomorrow"
+	actionChangeHours   = "/api/v1/actions/hours"
+)
+
+// cachedWindowTTLSeconds bounds how long a computed window can be actioned on via an
+// interactive message button before it expires from the KV store.
+const cachedWindowTTLSeconds = 10 * 60
+
+// cachedWindow is stashed in the KV store under a short-lived request ID so interactive
+// message actions can look up the window a /whentochat invocation computed for them.
+type cachedWindow struct {
+	ChannelID string   `json:"channel_id"`
+	UserID    string   `json:"user_id"`
+	UserIDs   []string `json:"user_ids"`
+	Window    Window   `json:"window"`
+}
+
+func cachedWindowKVKey(requestID string) string {
+	return "req_" + requestID
+}
+
+func newRequestID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.Wrap(err, "failed to generate request id")
+	}
+	return fmt.Sprintf("%x", b), nil
+}
+
+// cacheWindow stashes w under a freshly generated request ID so a later interactive
+// message action can look it back up, and returns that ID.
+func (p *Plugin) cacheWindow(channelID, userID string, users []*model.User, w Window) (string, error) {
+	requestID, err := newRequestID()
+	if err != nil {
+		return "", err
+	}
+
+	userIDs := make([]string, len(users))
+	for i, user := range users {
+		userIDs[i] = user.Id
+	}
+
+	data, err := json.Marshal(cachedWindow{ChannelID: channelID, UserID: userID, UserIDs: userIDs, Window: w})
+	if err != nil {
+		return "", errors.Wrap(err, "failed to marshal cached window")
+	}
+
+	if appErr := p.API.KVSetWithExpiry(cachedWindowKVKey(requestID), data, cachedWindowTTLSeconds); appErr != nil {
+		return "", errors.New(appErr.Error())
+	}
+
+	return requestID, nil
+}
+
+func (p *Plugin) getCachedWindow(requestID string) (cachedWindow, error) {
+	data, appErr := p.API.KVGet(cachedWindowKVKey(requestID))
+	if appErr != nil {
+		return cachedWindow{}, errors.New(appErr.Error())
+	}
+	if data == nil {
+		return cachedWindow{}, errors.New("this suggestion has expired, run /whentochat find again")
+	}
+
+	var cw cachedWindow
+	if err := json.Unmarshal(data, &cw); err != nil {
+		return cachedWindow{}, errors.Wrap(err, "failed to unmarshal cached window")
+	}
+
+	return cw, nil
+}
+
+func (p *Plugin) usersByIDs(ids []string) ([]*model.User, error) {
+	users := make([]*model.User, 0, len(ids))
+	for _, id := range ids {
+		user, appErr := p.API.GetUser(id)
+		if appErr != nil {
+			return nil, errors.New(appErr.Error())
+		}
+		users = append(users, user)
+	}
+	return users, nil
+}
+
+// schedulingAttachment builds the interactive message attachment offered alongside a
+// computed window, whose actions are handled by ServeHTTP.
+func schedulingAttachment(requestID string) *model.SlackAttachment {
+	actionContext := map[string]interface{}{"request_id": requestID}
+
+	action := func(name, path string) *model.PostAction {
+		return &model.PostAction{
+			Name: name,
+			Integration: &model.PostActionIntegration{
+				URL:     fmt.Sprintf("/plugins/%s%s", manifest.Id, path),
+				Context: actionContext,
+			},
+		}
+	}
+
+	return &model.SlackAttachment{
+		Actions: []*model.PostAction{
+			action("Post to channel", actionPostToChannel),
+			action("Add to calendar", actionICS),
+			action("Try tomorrow", actionTryTomorrow),
+			action("Change my hours", actionChangeHours),
+		},
+	}
+}
+
+// ServeHTTP dispatches interactive message actions posted back by the Mattermost server.
+func (p *Plugin) ServeHTTP(c *plugin.Context, w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Mattermost-User-Id") == "" {
+		http.Error(w, "not authorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.URL.Path {
+	case actionPostToChannel:
+		p.handlePostToChannel(w, r)
+	case actionICS:
+		p.handleAddToCalendar(w, r)
+	case actionTryTomorrow:
+		p.handleTryTomorrow(w, r)
+	case actionChangeHours:
+		p.handleChangeHours(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func decodeActionRequest(r *http.Request) (string, error) {
+	req := model.PostActionIntegrationRequestFromJson(r.Body)
+	if req == nil {
+		return "", errors.New("failed to decode action request")
+	}
+
+	requestID, _ := req.Context["request_id"].(string)
+	if requestID == "" {
+		return "", errors.New("missing request_id in action context")
+	}
+
+	return requestID, nil
+}
+
+func writeActionResponse(w http.ResponseWriter, resp *model.PostActionIntegrationResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(resp.ToJson())
+}
+
+func (p *Plugin) handlePostToChannel(w http.ResponseWriter, r *http.Request) {
+	requestID, err := decodeActionRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	cached, err := p.getCachedWindow(requestID)
+	if err != nil {
+		writeActionResponse(w, &model.PostActionIntegrationResponse{EphemeralText: err.Error()})
+		return
+	}
+
+	users, err := p.usersByIDs(cached.UserIDs)
+	if err != nil {
+		writeActionResponse(w, &model.PostActionIntegrationResponse{EphemeralText: err.Error()})
+		return
+	}
+
+	post := &model.Post{
+		UserId:    p.BotUserID,
+		ChannelId: cached.ChannelID,
+		Message:   "It looks like the best time to chat is:\n" + verboseDisplay([]Window{cached.Window}, users),
+	}
+	if _, appErr := p.API.CreatePost(post); appErr != nil {
+		writeActionResponse(w, &model.PostActionIntegrationResponse{EphemeralText: appErr.Error()})
+		return
+	}
+
+	writeActionResponse(w, &model.PostActionIntegrationResponse{EphemeralText: "Posted to the channel."})
+}
+
+// handleAddToCalendar responds with a .ics attachment for the cached window, generated by
+// BuildICS against the RFC 5545 grammar (see ical.go) rather than the ad-hoc text format an
+// earlier revision of this handler produced.
+func (p *Plugin) handleAddToCalendar(w http.ResponseWriter, r *http.Request) {
+	requestID, err := decodeActionRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	cached, err := p.getCachedWindow(requestID)
+	if err != nil {
+		writeActionResponse(w, &model.PostActionIntegrationResponse{EphemeralText: err.Error()})
+		return
+	}
+
+	users, err := p.usersByIDs(cached.UserIDs)
+	if err != nil {
+		writeActionResponse(w, &model.PostActionIntegrationResponse{EphemeralText: err.Error()})
+		return
+	}
+
+	ics, err := BuildICS("Chat", cached.Window.Start, cached.Window.End, users)
+	if err != nil {
+		writeActionResponse(w, &model.PostActionIntegrationResponse{EphemeralText: err.Error()})
+		return
+	}
+
+	fileInfo, appErr := p.API.UploadFile(ics, cached.ChannelID, "meeting.ics")
+	if appErr != nil {
+		writeActionResponse(w, &model.PostActionIntegrationResponse{EphemeralText: appErr.Error()})
+		return
+	}
+
+	post := &model.Post{
+		UserId:    p.BotUserID,
+		ChannelId: cached.ChannelID,
+		Message:   "Here's a calendar invite for the suggested time.",
+		FileIds:   []string{fileInfo.Id},
+	}
+	_ = p.API.SendEphemeralPost(cached.UserID, post)
+
+	writeActionResponse(w, &model.PostActionIntegrationResponse{EphemeralText: "Calendar file ready."})
+}
+
+func (p *Plugin) handleTryTomorrow(w http.ResponseWriter, r *http.Request) {
+	requestID, err := decodeActionRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	cached, err := p.getCachedWindow(requestID)
+	if err != nil {
+		writeActionResponse(w, &model.PostActionIntegrationResponse{EphemeralText: err.Error()})
+		return
+	}
+
+	users, err := p.usersByIDs(cached.UserIDs)
+	if err != nil {
+		writeActionResponse(w, &model.PostActionIntegrationResponse{EphemeralText: err.Error()})
+		return
+	}
+
+	var next *Window
+	for _, candidate := range p.windows(r.Context(), users, defaultSearchDays, defaultMinDurationMinutes) {
+		if candidate.Start.After(cached.Window.Start) {
+			c := candidate
+			next = &c
+			break
+		}
+	}
+	if next == nil {
+		writeActionResponse(w, &model.PostActionIntegrationResponse{EphemeralText: "No later window was found in the next week."})
+		return
+	}
+
+	newRequestID, err := p.cacheWindow(cached.ChannelID, cached.UserID, users, *next)
+	if err != nil {
+		writeActionResponse(w, &model.PostActionIntegrationResponse{EphemeralText: err.Error()})
+		return
+	}
+
+	writeActionResponse(w, &model.PostActionIntegrationResponse{
+		Update: &model.Post{
+			Message: "It looks like the next best time to chat is:\n" + verboseDisplay([]Window{*next}, users),
+			Props: map[string]interface{}{
+				"attachments": []*model.SlackAttachment{schedulingAttachment(newRequestID)},
+			},
+		},
+	})
+}
+
+func (p *Plugin) handleChangeHours(w http.ResponseWriter, r *http.Request) {
+	writeActionResponse(w, &model.PostActionIntegrationResponse{
+		EphemeralText: "Run `/whentochat set-hours HH:MM-HH:MM` to update your working hours.",
+	})
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/pkg/errors"
+)
+
+const icsDateTimeLayout = "20060102T150405Z"
+
+// BuildICS renders a minimal RFC 5545 VCALENDAR/VEVENT for the given summary, time range
+// and attendees, suitable for importing into Google Calendar, Outlook, etc.
+func BuildICS(summary string, start, end time.Time, attendees []*model.User) ([]byte, error) {
+	uid, err := newICSUID()
+	if err != nil {
+		return nil, err
+	}
+
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//whentochat//EN",
+		"BEGIN:VEVENT",
+		"UID:" + uid + "@whentochat",
+		"DTSTAMP:" + time.Now().UTC().Format(icsDateTimeLayout),
+		"DTSTART:" + start.UTC().Format(icsDateTimeLayout),
+		"DTEND:" + end.UTC().Format(icsDateTimeLayout),
+		"SUMMARY:" + escapeICSText(summary),
+	}
+
+	for _, attendee := range attendees {
+		lines = append(lines, fmt.Sprintf("ATTENDEE;CN=%s:mailto:%s",
+			escapeICSText(attendee.GetDisplayName("full_name")), attendee.Email))
+	}
+
+	lines = append(lines, "END:VEVENT", "END:VCALENDAR")
+
+	var buf bytes.Buffer
+	for _, line := range lines {
+		buf.WriteString(foldICSLine(line))
+		buf.WriteString("\r\n")
+	}
+
+	return buf.Bytes(), nil
+}
+
+func newICSUID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.Wrap(err, "failed to generate UID")
+	}
+	return fmt.Sprintf("%x", b), nil
+}
+
+// escapeICSText escapes commas, semicolons, backslashes and newlines per RFC 5545 §3.3.11.
+func escapeICSText(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, ";", `\;`)
+	s = strings.ReplaceAll(s, ",", `\,`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return s
+}
+
+// foldICSLine folds a content line at 75 octets as required by RFC 5545 §3.1, inserting
+// a CRLF followed by a single leading space before each continuation. The leading space
+// counts against the 75-octet budget of the line it starts, and lines are only ever split
+// on rune boundaries so multi-byte UTF-8 characters are never torn in half.
+func foldICSLine(line string) string {
+	const maxOctets = 75
+
+	if len(line) <= maxOctets {
+		return line
+	}
+
+	var folded strings.Builder
+	budget := maxOctets
+	var chunk strings.Builder
+	octets := 0
+
+	flush := func() {
+		if folded.Len() > 0 {
+			folded.WriteString("\r\n ")
+		}
+		folded.WriteString(chunk.String())
+		chunk.Reset()
+		octets = 0
+		budget = maxOctets - 1
+	}
+
+	for _, r := range line {
+		n := len(string(r))
+		if octets+n > budget {
+			flush()
+		}
+		chunk.WriteRune(r)
+		octets += n
+	}
+	flush()
+
+	return folded.String()
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEscapeICSText(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"no special characters", "Chat with Bob", "Chat with Bob"},
+		{"comma", "Bob, Alice", `Bob\, Alice`},
+		{"semicolon", "Bob; Alice", `Bob\; Alice`},
+		{"backslash", `C:\path`, `C:\\path`},
+		{"newline", "line one\nline two", `line one\nline two`},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := escapeICSText(c.in); got != c.want {
+				t.Errorf("escapeICSText(%q) = %q, want %q", c.in, got, c.want)
+			}
+		})
+	}
+}
+
+func TestFoldICSLine(t *testing.T) {
+	t.Run("short line is untouched", func(t *testing.T) {
+		line := "SUMMARY:Chat"
+		if got := foldICSLine(line); got != line {
+			t.Errorf("foldICSLine(%q) = %q, want unchanged", line, got)
+		}
+	})
+
+	t.Run("continuation lines never exceed 75 octets", func(t *testing.T) {
+		line := "SUMMARY:" + strings.Repeat("x", 200)
+		folded := foldICSLine(line)
+		for i, part := range strings.Split(folded, "\r\n") {
+			content := part
+			if i > 0 {
+				if !strings.HasPrefix(content, " ") {
+					t.Fatalf("continuation line %q is missing its leading space", content)
+				}
+			}
+			if n := len([]byte(content)); n > 75 {
+				t.Errorf("line %d (%q) is %d octets, want <= 75", i, content, n)
+			}
+		}
+	})
+
+	t.Run("multi-byte runes are never split across a fold", func(t *testing.T) {
+		// Each "é" is 2 octets in UTF-8; repeating it forces a fold right at the boundary.
+		line := "SUMMARY:" + strings.Repeat("é", 60)
+		folded := foldICSLine(line)
+		if !strings.Contains(folded, "é") {
+			t.Fatalf("expected folded output to still contain valid runes, got %q", folded)
+		}
+		for _, part := range strings.Split(folded, "\r\n") {
+			if !isValidUTF8Line(part) {
+				t.Errorf("fold produced an invalid UTF-8 line: %q", part)
+			}
+		}
+	})
+}
+
+func isValidUTF8Line(s string) bool {
+	for _, r := range s {
+		if r == '\ufffd' {
+			return false
+		}
+	}
+	return true
+}
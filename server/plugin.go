@@ -1,8 +1,8 @@
 package main
 
 import (
+	"context"
 	"fmt"
-	"net/http"
 	"strconv"
 	"strings"
 	"sync"
@@ -11,10 +11,21 @@ import (
 	"github.com/mattermost/mattermost-server/v5/model"
 	"github.com/mattermost/mattermost-server/v5/plugin"
 	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
 )
 
 const maxDisplayUserBullets = 50
 
+// defaultCommandTimeout bounds how long a single /whentochat invocation may run before
+// its context is cancelled, so a large channel can't block the plugin process.
+const defaultCommandTimeout = 5 * time.Second
+
+// allUsersPageSize is the page size used when listing channel members.
+const allUsersPageSize = 100
+
+// allUsersConcurrency caps how many member pages are fetched at once.
+const allUsersConcurrency = 4
+
 var errMaxChannelMembers = errors.New("max channel members")
 
 // Plugin implements the interface expected by the Mattermost server to communicate between the server and plugin processes.
@@ -48,6 +59,7 @@ func (p *Plugin) OnActivate() error {
 		AutoComplete:     true,
 		AutoCompleteDesc: "Find a time to chat!",
 		DisplayName:      "When To Chat",
+		AutocompleteData: getAutocompleteData(),
 	}
 	err = p.API.RegisterCommand(command)
 	if err != nil {
@@ -58,75 +70,98 @@ func (p *Plugin) OnActivate() error {
 }
 
 func (p *Plugin) ExecuteCommand(c *plugin.Context, args *model.CommandArgs) (*model.CommandResponse, *model.AppError) {
-	configuration := p.getConfiguration()
-
 	split := strings.Fields(args.Command)
-	command := split[0]
-	if command != "/whentochat" {
+	if len(split) == 0 || split[0] != "/whentochat" {
 		return &model.CommandResponse{}, nil
 	}
 
-	allUsers, err := p.allUsers(args.ChannelId, configuration.MaxChannelMembers)
-	if err != nil && !errors.Is(err, errMaxChannelMembers) {
-		return nil, model.NewAppError("whentochat (*Plugin).ExecuteCommand", "", nil, err.Error(), http.StatusInternalServerError)
+	subCommand := defaultSubCommand
+	var parameters []string
+	if len(split) > 1 {
+		subCommand = split[1]
+		parameters = split[2:]
 	}
 
-	post := &model.Post{
-		UserId:    p.BotUserID,
-		ChannelId: args.ChannelId,
+	handler, ok := commandHandlers[subCommand]
+	if !ok {
+		return p.postCommandResponse(args, fmt.Sprintf("Unknown command `%s`. Try `/whentochat help`.", subCommand)), nil
 	}
 
-	if err != nil && errors.Is(err, errMaxChannelMembers) {
-		post.Message = "Too many channel members."
-		_ = p.API.SendEphemeralPost(args.UserId, post)
-		return &model.CommandResponse{}, nil
-	}
+	ctx, cancel := context.WithTimeout(context.Background(), p.commandTimeout())
+	defer cancel()
 
-	earliestStart, latestEnd, ok := window(allUsers)
+	return handler(p, ctx, args, parameters)
+}
 
-	if !ok {
-		post.Message = "There is no window that suits everyone."
-		_ = p.API.SendEphemeralPost(args.UserId, post)
-		return &model.CommandResponse{}, nil
+// commandTimeout returns the configured per-command deadline, falling back to
+// defaultCommandTimeout when the admin hasn't set one (or set an invalid value).
+func (p *Plugin) commandTimeout() time.Duration {
+	seconds := p.getConfiguration().CommandTimeoutSeconds
+	if seconds <= 0 {
+		return defaultCommandTimeout
 	}
+	return time.Duration(seconds) * time.Second
+}
 
-	allUsers = arrangeUserFirst(args.UserId, allUsers)
+// allUsers fetches every non-bot member of channelID. It first looks up the channel's
+// member count via GetChannelStats so it knows how many pages to expect, then fetches
+// those pages concurrently with a bounded worker pool, honoring ctx cancellation between
+// batches. limit is enforced against the non-bot count, same as the sequential
+// implementation this replaced.
+//
+// MemberCount includes bots, so it only sizes the page fetch; a channel whose MemberCount
+// is over limit can still pass once its bots are filtered out, which is why the limit is
+// checked after the fetch.
+func (p *Plugin) allUsers(ctx context.Context, channelID string, limit int) ([]*model.User, error) {
+	stats, appErr := p.API.GetChannelStats(channelID)
+	if appErr != nil {
+		return nil, errors.New(appErr.Error())
+	}
 
-	var message string
-	if len(allUsers) <= maxDisplayUserBullets {
-		message = verboseDisplay(earliestStart, latestEnd, allUsers)
-	} else {
-		message = compactDisplay(earliestStart, latestEnd, allUsers)
+	pageCount := (int(stats.MemberCount) + allUsersPageSize - 1) / allUsersPageSize
+	pages := make([][]*model.User, pageCount)
+
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(allUsersConcurrency)
+
+	for page := 0; page < pageCount; page++ {
+		page := page
+		g.Go(func() error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			usersBatch, err := p.API.GetUsersInChannel(channelID, "username", page, allUsersPageSize)
+			if err != nil {
+				return errors.New(err.Error())
+			}
+
+			nonBots := make([]*model.User, 0, len(usersBatch))
+			for _, user := range usersBatch {
+				if user.IsBot {
+					continue
+				}
+				nonBots = append(nonBots, user)
+			}
+			pages[page] = nonBots
+
+			return nil
+		})
 	}
 
-	post.Message = "It looks like the best times to chat are:\n" + message
-	_ = p.API.SendEphemeralPost(args.UserId, post)
-	return &model.CommandResponse{}, nil
-}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
 
-func (p *Plugin) allUsers(channelID string, limit int) ([]*model.User, error) {
 	var allUsers []*model.User
-	var page int
-	const batchSize = 100
-	for {
-		usersBatch, err := p.API.GetUsersInChannel(channelID, "username", page, batchSize)
-		if err != nil {
-			return nil, errors.New(err.Error())
-		}
-		for _, user := range usersBatch {
-			if user.IsBot {
-				continue
-			}
-			allUsers = append(allUsers, user)
-		}
-		if len(allUsers) > limit {
-			return allUsers, errMaxChannelMembers
-		}
-		if len(usersBatch) < batchSize {
-			break
-		}
-		page++
+	for _, page := range pages {
+		allUsers = append(allUsers, page...)
 	}
+
+	if len(allUsers) > limit {
+		return allUsers, errMaxChannelMembers
+	}
+
 	return allUsers, nil
 }
 
@@ -152,38 +187,6 @@ func location(user *model.User) *time.Location {
 	return location
 }
 
-func window(users []*model.User) (start, end time.Time, ok bool) {
-	for i, user := range users {
-		loc := location(user)
-		if loc == nil {
-			continue
-		}
-
-		now := time.Now()
-		userEarliestStart := time.Date(now.Year(), now.Month(), now.Day(), 7, 0, 0, 0, loc)
-		userLatestEnd := time.Date(now.Year(), now.Month(), now.Day(), 22, 0, 0, 0, loc)
-
-		if i == 0 {
-			start = userEarliestStart
-			end = userLatestEnd
-		}
-
-		if userEarliestStart.After(start) {
-			start = userEarliestStart
-		}
-
-		if userLatestEnd.Before(end) {
-			end = userLatestEnd
-		}
-
-		if start.After(end) || start.Equal(end) {
-			return
-		}
-	}
-	ok = true
-	return
-}
-
 func arrangeUserFirst(userID string, users []*model.User) []*model.User {
 	var indexOfUser int
 	for i, user := range users {
@@ -198,51 +201,57 @@ func arrangeUserFirst(userID string, users []*model.User) []*model.User {
 	return sorted
 }
 
-func verboseDisplay(start, end time.Time, users []*model.User) string {
+func verboseDisplay(windows []Window, users []*model.User) string {
 	var message string
-	for _, user := range users {
-		loc := location(user)
-		if loc == nil {
-			message = fmt.Sprintf("%s\n- %s %s: ?", message, user.FirstName, user.LastName)
-			continue
+	for _, w := range windows {
+		message = fmt.Sprintf("%s\n%s", message, windowHeader(w))
+		for _, user := range users {
+			loc := location(user)
+			if loc == nil {
+				message = fmt.Sprintf("%s\n- %s %s: ?", message, user.FirstName, user.LastName)
+				continue
+			}
+			walltimeStart := w.Start.In(loc)
+			walltimeEnd := w.End.In(loc)
+			timeLayout := "3:04pm"
+			message = fmt.Sprintf("%s\n- %s: %s - %s %s", message, user.GetDisplayName("full_name"),
+				walltimeStart.Format(timeLayout),
+				walltimeEnd.Format(timeLayout),
+				walltimeEnd.Format("(MST)"))
 		}
-		walltimeStart := start.In(loc)
-		walltimeEnd := end.In(loc)
-		timeLayout := "3:04pm"
-		message = fmt.Sprintf("%s\n- %s: %s - %s %s", message, user.GetDisplayName("full_name"),
-			walltimeStart.Format(timeLayout),
-			walltimeEnd.Format(timeLayout),
-			walltimeEnd.Format("(MST)"))
 	}
 	return message
 }
 
-func compactDisplay(start, end time.Time, users []*model.User) string {
+func compactDisplay(windows []Window, users []*model.User) string {
 	var message string
 
 	usersMap := usersByTimezone(users)
 
-	for _, users := range usersMap {
-		loc := location(users[0])
-
-		var othersMsg string
-		userCount := len(users)
-		if userCount > 1 {
-			if userCount == 2 {
-				othersMsg = " and 1 other"
-			} else {
-				othersMsg = fmt.Sprintf(" and %d others", userCount-1)
+	for _, w := range windows {
+		message = fmt.Sprintf("%s\n%s", message, windowHeader(w))
+		for _, users := range usersMap {
+			loc := location(users[0])
+
+			var othersMsg string
+			userCount := len(users)
+			if userCount > 1 {
+				if userCount == 2 {
+					othersMsg = " and 1 other"
+				} else {
+					othersMsg = fmt.Sprintf(" and %d others", userCount-1)
+				}
 			}
-		}
 
-		walltimeStart := start.In(loc)
-		walltimeEnd := end.In(loc)
-		timeLayout := "3:04pm"
-		message = fmt.Sprintf("%s\n- %s%s: %s - %s %s", message, users[0].GetDisplayName("full_name"),
-			othersMsg,
-			walltimeStart.Format(timeLayout),
-			walltimeEnd.Format(timeLayout),
-			walltimeEnd.Format("(MST)"))
+			walltimeStart := w.Start.In(loc)
+			walltimeEnd := w.End.In(loc)
+			timeLayout := "3:04pm"
+			message = fmt.Sprintf("%s\n- %s%s: %s - %s %s", message, users[0].GetDisplayName("full_name"),
+				othersMsg,
+				walltimeStart.Format(timeLayout),
+				walltimeEnd.Format(timeLayout),
+				walltimeEnd.Format("(MST)"))
+		}
 	}
 
 	return message
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin/plugintest"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAllUsers(t *testing.T) {
+	t.Run("bots in MemberCount do not count against limit", func(t *testing.T) {
+		api := &plugintest.API{}
+		api.On("GetChannelStats", "channel1").Return(&model.ChannelStats{ChannelId: "channel1", MemberCount: 3}, nil)
+		api.On("GetUsersInChannel", "channel1", "username", 0, allUsersPageSize).Return([]*model.User{
+			{Id: "human1"},
+			{Id: "bot1", IsBot: true},
+			{Id: "human2"},
+		}, nil)
+		defer api.AssertExpectations(t)
+
+		p := &Plugin{}
+		p.SetAPI(api)
+
+		users, err := p.allUsers(context.Background(), "channel1", 2)
+		require.NoError(t, err)
+
+		api.AssertCalled(t, "GetUsersInChannel", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		require.Len(t, users, 2)
+	})
+
+	t.Run("fetches and filters bots when MemberCount is within limit", func(t *testing.T) {
+		api := &plugintest.API{}
+		api.On("GetChannelStats", "channel1").Return(&model.ChannelStats{ChannelId: "channel1", MemberCount: 3}, nil)
+		api.On("GetUsersInChannel", "channel1", "username", 0, allUsersPageSize).Return([]*model.User{
+			{Id: "human1"},
+			{Id: "bot1", IsBot: true},
+			{Id: "human2"},
+		}, nil)
+		defer api.AssertExpectations(t)
+
+		p := &Plugin{}
+		p.SetAPI(api)
+
+		users, err := p.allUsers(context.Background(), "channel1", 50)
+		require.NoError(t, err)
+		require.Len(t, users, 2)
+	})
+
+	t.Run("rejects on the boundary check once bots are filtered out", func(t *testing.T) {
+		api := &plugintest.API{}
+		api.On("GetChannelStats", "channel1").Return(&model.ChannelStats{ChannelId: "channel1", MemberCount: 2}, nil)
+		api.On("GetUsersInChannel", "channel1", "username", 0, allUsersPageSize).Return([]*model.User{
+			{Id: "human1"},
+			{Id: "human2"},
+		}, nil)
+		defer api.AssertExpectations(t)
+
+		p := &Plugin{}
+		p.SetAPI(api)
+
+		users, err := p.allUsers(context.Background(), "channel1", 1)
+		require.ErrorIs(t, err, errMaxChannelMembers)
+		require.Len(t, users, 2)
+	})
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// defaultEarliestHour, defaultLatestHour and defaultWorkdays are the channel-wide
+// fallback preferences applied to any user who hasn't configured their own via
+// `/whentochat set-hours` or `/whentochat set-days`.
+const (
+	defaultEarliestHour = 7
+	defaultLatestHour   = 22
+)
+
+var defaultWorkdays = []time.Weekday{
+	time.Monday,
+	time.Tuesday,
+	time.Wednesday,
+	time.Thursday,
+	time.Friday,
+}
+
+// Preferences holds a single user's scheduling preferences, persisted in the plugin's
+// KV store keyed by user ID.
+type Preferences struct {
+	EarliestHour int            `json:"earliest_hour"`
+	LatestHour   int            `json:"latest_hour"`
+	Workdays     []time.Weekday `json:"workdays"`
+	Excluded     bool           `json:"excluded"`
+}
+
+func defaultPreferences() Preferences {
+	return Preferences{
+		EarliestHour: defaultEarliestHour,
+		LatestHour:   defaultLatestHour,
+		Workdays:     defaultWorkdays,
+	}
+}
+
+// worksOn reports whether the given weekday is one of the user's configured workdays.
+func (prefs Preferences) worksOn(day time.Weekday) bool {
+	for _, d := range prefs.Workdays {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+func prefsKVKey(userID string) string {
+	return "prefs_" + userID
+}
+
+// getPrefs returns userID's stored preferences, or the channel defaults if they
+// haven't configured any yet.
+func (p *Plugin) getPrefs(userID string) (Preferences, error) {
+	data, appErr := p.API.KVGet(prefsKVKey(userID))
+	if appErr != nil {
+		return Preferences{}, errors.New(appErr.Error())
+	}
+	if data == nil {
+		return defaultPreferences(), nil
+	}
+
+	var prefs Preferences
+	if err := json.Unmarshal(data, &prefs); err != nil {
+		return Preferences{}, errors.Wrap(err, "failed to unmarshal preferences")
+	}
+
+	return prefs, nil
+}
+
+// setPrefs persists userID's preferences.
+func (p *Plugin) setPrefs(userID string, prefs Preferences) error {
+	data, err := json.Marshal(prefs)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal preferences")
+	}
+
+	if appErr := p.API.KVSet(prefsKVKey(userID), data); appErr != nil {
+		return errors.New(appErr.Error())
+	}
+
+	return nil
+}
+
+func formatWorkdays(days []time.Weekday) string {
+	names := make([]string, len(days))
+	for i, d := range days {
+		names[i] = strings.ToLower(d.String()[:3])
+	}
+	return strings.Join(names, ", ")
+}
+
+func formatHours(prefs Preferences) string {
+	return fmt.Sprintf("%02d:00-%02d:00", prefs.EarliestHour, prefs.LatestHour)
+}
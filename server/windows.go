@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+const (
+	defaultSearchDays         = 7
+	defaultMinDurationMinutes = 30
+)
+
+// Window is a single candidate meeting slot, stored in UTC.
+type Window struct {
+	Start time.Time
+	End   time.Time
+}
+
+// windows scans the next `days` days and returns every day where the intersection of
+// everyone's working hours yields a slot of at least minDurationMinutes that hasn't
+// already elapsed. Preferences are fetched from the KV store once up front rather than
+// once per day, and the scan bails out early if ctx is cancelled.
+func (p *Plugin) windows(ctx context.Context, users []*model.User, days, minDurationMinutes int) []Window {
+	prefsByUser := p.prefsByUser(users)
+
+	var found []Window
+	now := time.Now()
+	minDuration := time.Duration(minDurationMinutes) * time.Minute
+
+	for offset := 0; offset < days; offset++ {
+		if ctx.Err() != nil {
+			break
+		}
+
+		day := now.AddDate(0, 0, offset)
+
+		start, end, ok := windowForDay(users, prefsByUser, day)
+		if !ok {
+			continue
+		}
+
+		// Today's window may have already started, or even ended entirely, by the time
+		// this runs. Clip a window in progress to now and drop one that's already over.
+		if start.Before(now) {
+			start = now
+		}
+		if !end.After(now) {
+			continue
+		}
+		if end.Sub(start) < minDuration {
+			continue
+		}
+
+		found = append(found, Window{Start: start.UTC(), End: end.UTC()})
+	}
+
+	return found
+}
+
+// prefsByUser fetches every user's preferences from the KV store in one pass, keyed by
+// user ID. A user whose preferences fail to load is simply absent from the map, and
+// windowForDay skips such a user exactly as if they'd been explicitly excluded.
+func (p *Plugin) prefsByUser(users []*model.User) map[string]Preferences {
+	prefsByUser := make(map[string]Preferences, len(users))
+	for _, user := range users {
+		prefs, err := p.getPrefs(user.Id)
+		if err != nil {
+			continue
+		}
+		prefsByUser[user.Id] = prefs
+	}
+	return prefsByUser
+}
+
+// windowForDay computes the intersection of every non-excluded user's working hours on
+// the given day, evaluated in each user's own timezone and workday configuration. If any
+// counted user doesn't work on that day, there is no window.
+func windowForDay(users []*model.User, prefsByUser map[string]Preferences, day time.Time) (start, end time.Time, ok bool) {
+	var counted int
+	for _, user := range users {
+		prefs, known := prefsByUser[user.Id]
+		if !known || prefs.Excluded {
+			continue
+		}
+
+		loc := location(user)
+		if loc == nil {
+			continue
+		}
+
+		localDay := day.In(loc)
+		if !prefs.worksOn(localDay.Weekday()) {
+			return start, end, false
+		}
+
+		userEarliestStart := time.Date(localDay.Year(), localDay.Month(), localDay.Day(), prefs.EarliestHour, 0, 0, 0, loc)
+		userLatestEnd := time.Date(localDay.Year(), localDay.Month(), localDay.Day(), prefs.LatestHour, 0, 0, 0, loc)
+
+		if counted == 0 {
+			start = userEarliestStart
+			end = userLatestEnd
+		}
+		counted++
+
+		if userEarliestStart.After(start) {
+			start = userEarliestStart
+		}
+
+		if userLatestEnd.Before(end) {
+			end = userLatestEnd
+		}
+
+		if start.After(end) || start.Equal(end) {
+			return start, end, false
+		}
+	}
+
+	if counted == 0 {
+		return start, end, false
+	}
+
+	ok = true
+	return
+}
+
+func windowHeader(w Window) string {
+	return fmt.Sprintf("%s %s-%s UTC", w.Start.Format("Mon"), w.Start.Format("15:04"), w.End.Format("15:04"))
+}
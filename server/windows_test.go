@@ -0,0 +1,73 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+func testUser(id, manualTimezone string) *model.User {
+	return &model.User{
+		Id: id,
+		Timezone: map[string]string{
+			"useAutomaticTimezone": "false",
+			"manualTimezone":       manualTimezone,
+		},
+	}
+}
+
+func TestWindowForDay(t *testing.T) {
+	alice := testUser("alice", "America/New_York")
+	bob := testUser("bob", "Europe/London")
+	users := []*model.User{alice, bob}
+
+	// 2023-01-02 is a Monday.
+	day := time.Date(2023, time.January, 2, 12, 0, 0, 0, time.UTC)
+
+	t.Run("intersects overlapping hours across timezones", func(t *testing.T) {
+		prefsByUser := map[string]Preferences{
+			"alice": {EarliestHour: 9, LatestHour: 17, Workdays: defaultWorkdays},
+			"bob":   {EarliestHour: 9, LatestHour: 17, Workdays: defaultWorkdays},
+		}
+
+		start, end, ok := windowForDay(users, prefsByUser, day)
+		if !ok {
+			t.Fatalf("expected a window, got none")
+		}
+		if !start.Before(end) {
+			t.Fatalf("expected start before end, got start=%v end=%v", start, end)
+		}
+	})
+
+	t.Run("excluded user drops out of the intersection", func(t *testing.T) {
+		prefsByUser := map[string]Preferences{
+			"alice": {EarliestHour: 9, LatestHour: 17, Workdays: defaultWorkdays, Excluded: true},
+			"bob":   {EarliestHour: 9, LatestHour: 17, Workdays: defaultWorkdays},
+		}
+
+		_, _, ok := windowForDay(users, prefsByUser, day)
+		if !ok {
+			t.Fatalf("expected bob's hours alone to still produce a window")
+		}
+	})
+
+	t.Run("missing preferences behave like an excluded user", func(t *testing.T) {
+		_, _, ok := windowForDay([]*model.User{alice}, map[string]Preferences{}, day)
+		if ok {
+			t.Fatalf("expected no window when no preferences are known for the only user")
+		}
+	})
+
+	t.Run("a non-workday yields no window", func(t *testing.T) {
+		prefsByUser := map[string]Preferences{
+			"alice": {EarliestHour: 9, LatestHour: 17, Workdays: []time.Weekday{time.Sunday}},
+			"bob":   {EarliestHour: 9, LatestHour: 17, Workdays: defaultWorkdays},
+		}
+
+		_, _, ok := windowForDay(users, prefsByUser, day)
+		if ok {
+			t.Fatalf("expected no window when alice doesn't work on this day")
+		}
+	})
+}